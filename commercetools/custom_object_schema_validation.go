@@ -0,0 +1,143 @@
+package commercetools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+
+	jsonschema "github.com/santhosh-tekuri/jsonschema"
+
+	"github.com/labd/commercetools-go-sdk/commercetools"
+)
+
+// customObjectSchemaContainer is the well-known container
+// commercetools_custom_object_schema resources are stored under, so a
+// commercetools_custom_object resource can reference a shared schema by key
+// via `schema_ref` instead of inlining it with `schema_json`.
+const customObjectSchemaContainer = "terraform/schemas"
+
+// resourceGetter is satisfied by both *schema.ResourceData and
+// *schema.ResourceDiff, letting the schema resolution/validation helpers run
+// identically from CustomizeDiff and from the CRUD functions.
+type resourceGetter interface {
+	Get(key string) interface{}
+}
+
+var compiledCustomObjectSchemas sync.Map // raw schema text -> *jsonschema.Schema
+
+// _compileJSONSchema compiles a raw JSON Schema document and caches the
+// result, since the same schema is typically compiled once per plan
+// (CustomizeDiff) and again per apply (Create/Update).
+func _compileJSONSchema(raw string) (*jsonschema.Schema, error) {
+	if cached, ok := compiledCustomObjectSchemas.Load(raw); ok {
+		return cached.(*jsonschema.Schema), nil
+	}
+
+	compiler := jsonschema.NewCompiler()
+	// Defaulting relies on the schema's "default" keyword ending up on the
+	// compiled *jsonschema.Schema; the library treats it as an annotation and
+	// discards it unless extraction is explicitly requested.
+	compiler.ExtractAnnotations = true
+	if err := compiler.AddResource("schema.json", strings.NewReader(raw)); err != nil {
+		return nil, fmt.Errorf("could not parse schema_json: %w", err)
+	}
+	compiled, err := compiler.Compile("schema.json")
+	if err != nil {
+		return nil, fmt.Errorf("could not compile schema_json: %w", err)
+	}
+
+	compiledCustomObjectSchemas.Store(raw, compiled)
+	return compiled, nil
+}
+
+// _resolveCustomObjectSchema returns the raw JSON Schema text a
+// commercetools_custom_object should validate/default `value` against,
+// preferring the inline `schema_json` and falling back to looking up
+// `schema_ref` in the shared schema container.
+func _resolveCustomObjectSchema(ctx context.Context, client *commercetools.Client, d resourceGetter) (string, error) {
+	if inline, ok := d.Get("schema_json").(string); ok && inline != "" {
+		return inline, nil
+	}
+
+	ref, ok := d.Get("schema_ref").(string)
+	if !ok || ref == "" {
+		return "", nil
+	}
+
+	stored, err := client.CustomObjectGetWithContainerAndKey(ctx, customObjectSchemaContainer, ref)
+	if err != nil {
+		return "", fmt.Errorf("could not load schema_ref %q: %w", ref, err)
+	}
+	return _extractStoredCustomObjectSchema(stored.Value)
+}
+
+func _extractStoredCustomObjectSchema(value interface{}) (string, error) {
+	data, ok := value.(map[string]interface{})
+	if !ok {
+		return "", fmt.Errorf("schema custom object has an unexpected shape")
+	}
+	raw, ok := data["schema"]
+	if !ok {
+		return "", fmt.Errorf("schema custom object is missing its \"schema\" field")
+	}
+	encoded, err := json.Marshal(raw)
+	if err != nil {
+		return "", fmt.Errorf("could not encode stored schema: %w", err)
+	}
+	return string(encoded), nil
+}
+
+// _validateAndDefaultCustomObjectValue validates raw against compiled,
+// reporting the first failure together with its JSON pointer, then returns
+// the decoded value with schema-declared defaults applied on top of it. This
+// mirrors how the Kubernetes API server runs conversion and defaulting
+// before persistence.
+func _validateAndDefaultCustomObjectValue(compiled *jsonschema.Schema, raw string) (interface{}, error) {
+	var decoded interface{}
+	if err := json.Unmarshal([]byte(raw), &decoded); err != nil {
+		return nil, fmt.Errorf("could not parse value as JSON: %w", err)
+	}
+
+	if err := compiled.ValidateInterface(decoded); err != nil {
+		if validationErr, ok := err.(*jsonschema.ValidationError); ok {
+			for len(validationErr.Causes) > 0 {
+				validationErr = validationErr.Causes[0]
+			}
+			return nil, fmt.Errorf("value failed schema validation at %s: %s", validationErr.InstancePtr, validationErr.Message)
+		}
+		return nil, fmt.Errorf("value failed schema validation: %w", err)
+	}
+
+	_applyCustomObjectSchemaDefaults(compiled, &decoded)
+	return decoded, nil
+}
+
+// _applyCustomObjectSchemaDefaults recursively fills in schema-declared
+// `default` values for object properties that are absent from value.
+func _applyCustomObjectSchemaDefaults(s *jsonschema.Schema, value *interface{}) {
+	if s == nil || value == nil {
+		return
+	}
+
+	if obj, ok := (*value).(map[string]interface{}); ok {
+		for name, propSchema := range s.Properties {
+			child, exists := obj[name]
+			if !exists {
+				if propSchema.Default != nil {
+					obj[name] = propSchema.Default
+				}
+				continue
+			}
+			_applyCustomObjectSchemaDefaults(propSchema, &child)
+			obj[name] = child
+		}
+		*value = obj
+		return
+	}
+
+	if *value == nil && s.Default != nil {
+		*value = s.Default
+	}
+}
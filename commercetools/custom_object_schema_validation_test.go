@@ -0,0 +1,120 @@
+package commercetools
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestCompileJSONSchemaIsCached(t *testing.T) {
+	raw := `{"type":"object","properties":{"a":{"type":"string"}}}`
+
+	first, err := _compileJSONSchema(raw)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	second, err := _compileJSONSchema(raw)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if first != second {
+		t.Error("expected the cached schema to be reused across calls with identical raw text")
+	}
+}
+
+func TestCompileJSONSchemaInvalid(t *testing.T) {
+	if _, err := _compileJSONSchema(`not json`); err == nil {
+		t.Error("expected an error for invalid schema_json")
+	}
+}
+
+func TestValidateAndDefaultCustomObjectValue(t *testing.T) {
+	compiled, err := _compileJSONSchema(`{
+		"type": "object",
+		"properties": {
+			"name": {"type": "string"},
+			"enabled": {"type": "boolean", "default": true}
+		},
+		"required": ["name"]
+	}`)
+	if err != nil {
+		t.Fatalf("unexpected error compiling schema: %v", err)
+	}
+
+	t.Run("applies defaults for missing properties", func(t *testing.T) {
+		got, err := _validateAndDefaultCustomObjectValue(compiled, `{"name":"flag"}`)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		want := map[string]interface{}{"name": "flag", "enabled": true}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("got %v, want %v", got, want)
+		}
+	})
+
+	t.Run("fails validation for a missing required property", func(t *testing.T) {
+		if _, err := _validateAndDefaultCustomObjectValue(compiled, `{"enabled":true}`); err == nil {
+			t.Error("expected a validation error for a missing required property")
+		}
+	})
+
+	t.Run("rejects invalid JSON", func(t *testing.T) {
+		if _, err := _validateAndDefaultCustomObjectValue(compiled, `not json`); err == nil {
+			t.Error("expected an error for unparseable value")
+		}
+	})
+}
+
+func TestApplyCustomObjectSchemaDefaultsNested(t *testing.T) {
+	compiled, err := _compileJSONSchema(`{
+		"type": "object",
+		"properties": {
+			"nested": {
+				"type": "object",
+				"properties": {
+					"flag": {"type": "boolean", "default": false}
+				}
+			}
+		}
+	}`)
+	if err != nil {
+		t.Fatalf("unexpected error compiling schema: %v", err)
+	}
+
+	var value interface{} = map[string]interface{}{
+		"nested": map[string]interface{}{},
+	}
+	_applyCustomObjectSchemaDefaults(compiled, &value)
+
+	want := map[string]interface{}{
+		"nested": map[string]interface{}{"flag": false},
+	}
+	if !reflect.DeepEqual(value, want) {
+		t.Errorf("got %v, want %v", value, want)
+	}
+}
+
+func TestExtractStoredCustomObjectSchema(t *testing.T) {
+	t.Run("extracts the schema field", func(t *testing.T) {
+		got, err := _extractStoredCustomObjectSchema(map[string]interface{}{
+			"schema": map[string]interface{}{"type": "object"},
+		})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if got != `{"type":"object"}` {
+			t.Errorf("got %q", got)
+		}
+	})
+
+	t.Run("errors on missing schema field", func(t *testing.T) {
+		if _, err := _extractStoredCustomObjectSchema(map[string]interface{}{}); err == nil {
+			t.Error("expected an error for a missing schema field")
+		}
+	})
+
+	t.Run("errors on unexpected shape", func(t *testing.T) {
+		if _, err := _extractStoredCustomObjectSchema("not an object"); err == nil {
+			t.Error("expected an error for a non-object value")
+		}
+	})
+}
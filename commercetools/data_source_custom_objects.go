@@ -0,0 +1,214 @@
+package commercetools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/PaesslerAG/gval"
+	"github.com/PaesslerAG/jsonpath"
+	"github.com/hashicorp/terraform-plugin-sdk/helper/schema"
+	"github.com/labd/commercetools-go-sdk/commercetools"
+)
+
+// customObjectQueryPageSize is the number of custom objects requested per
+// query page. commercetools containers can hold far more objects than fit in
+// a single response, so results are streamed in chunks rather than fetched
+// with one unbounded query.
+const customObjectQueryPageSize = 500
+
+func dataSourceCustomObjects() *schema.Resource {
+	return &schema.Resource{
+		Description: "Look up all custom objects in a container, optionally narrowed by key prefix, a " +
+			"commercetools `where` predicate and/or a JSONPath filter evaluated against each object's `value`. " +
+			"Useful for patterns like looking up every feature flag in a container and feeding the result into " +
+			"another resource, without declaring each key individually.\n\n" +
+			"See also the [Custom Object API Documentation](https://docs.commercetools.com/api/projects/custom-objects)",
+		Read: dataSourceCustomObjectsRead,
+		Schema: map[string]*schema.Schema{
+			"container": {
+				Description: "The container to list custom objects from",
+				Type:        schema.TypeString,
+				Required:    true,
+			},
+			"key_prefix": {
+				Description: "Only return objects whose `key` starts with this prefix",
+				Type:        schema.TypeString,
+				Optional:    true,
+			},
+			"where": {
+				Description: "A commercetools query predicate, passed through to the Custom Objects query API",
+				Type:        schema.TypeString,
+				Optional:    true,
+			},
+			"jsonpath_filter": {
+				Description: "A JSONPath expression evaluated locally against each object's `value`. Objects for " +
+					"which the expression yields no result are excluded",
+				Type:     schema.TypeString,
+				Optional: true,
+			},
+			"include_total": {
+				Description: "Whether to also query the total number of matching objects in the container. " +
+					"Disabled by default since it makes commercetools count the matches on every page query",
+				Type:     schema.TypeBool,
+				Optional: true,
+				Default:  false,
+			},
+			"total": {
+				Description: "The total number of objects matching `container`/`key_prefix`/`where` (before " +
+					"`jsonpath_filter` is applied), only set when `include_total` is true",
+				Type:     schema.TypeInt,
+				Computed: true,
+			},
+			"results": {
+				Description: "The custom objects matching the given filters",
+				Type:        schema.TypeList,
+				Computed:    true,
+				Elem: &schema.Resource{
+					Schema: map[string]*schema.Schema{
+						"id": {
+							Type:     schema.TypeString,
+							Computed: true,
+						},
+						"container": {
+							Type:     schema.TypeString,
+							Computed: true,
+						},
+						"key": {
+							Type:     schema.TypeString,
+							Computed: true,
+						},
+						"version": {
+							Type:     schema.TypeInt,
+							Computed: true,
+						},
+						"value_json": {
+							Type:     schema.TypeString,
+							Computed: true,
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+func dataSourceCustomObjectsRead(d *schema.ResourceData, m interface{}) error {
+	client := getClient(m)
+	ctx := context.Background()
+
+	container := d.Get("container").(string)
+	keyPrefix := d.Get("key_prefix").(string)
+	where := d.Get("where").(string)
+	jsonPathFilter := d.Get("jsonpath_filter").(string)
+	includeTotal := d.Get("include_total").(bool)
+
+	predicates := _buildCustomObjectQueryPredicates(container, keyPrefix, where)
+
+	var filter gval.Evaluable
+	if jsonPathFilter != "" {
+		compiled, err := jsonpath.New(jsonPathFilter)
+		if err != nil {
+			return fmt.Errorf("could not parse jsonpath_filter %q: %w", jsonPathFilter, err)
+		}
+		filter = compiled
+	}
+
+	results := make([]map[string]interface{}, 0)
+	offset := 0
+	var total int
+
+	for {
+		input := &commercetools.QueryInput{
+			Where:  strings.Join(predicates, " and "),
+			Limit:  customObjectQueryPageSize,
+			Offset: offset,
+		}
+		if !includeTotal {
+			// withTotal defaults to true on the commercetools API; querying
+			// a large container without it avoids an extra count pass on
+			// every page.
+			input.Extra = url.Values{"withTotal": []string{"false"}}
+		}
+		page, err := client.CustomObjectQuery(ctx, input)
+		if err != nil {
+			return fmt.Errorf("could not query custom objects in container %s: %w", container, err)
+		}
+
+		for i := range page.Results {
+			customObject := page.Results[i]
+
+			if filter != nil && !_customObjectMatchesJSONPathFilter(ctx, filter, customObject.Value) {
+				continue
+			}
+
+			valueJSON, err := json.Marshal(customObject.Value)
+			if err != nil {
+				return fmt.Errorf("could not encode value of custom object %s: %w", customObject.ID, err)
+			}
+
+			results = append(results, map[string]interface{}{
+				"id":         customObject.ID,
+				"container":  customObject.Container,
+				"key":        customObject.Key,
+				"version":    customObject.Version,
+				"value_json": string(valueJSON),
+			})
+		}
+
+		if includeTotal {
+			total = page.Total
+		}
+
+		var done bool
+		offset, done = _nextCustomObjectQueryOffset(offset, len(page.Results), customObjectQueryPageSize)
+		if done {
+			break
+		}
+	}
+
+	d.SetId(strings.Join(predicates, "&"))
+	d.Set("results", results)
+	if includeTotal {
+		d.Set("total", total)
+	}
+
+	return nil
+}
+
+// _buildCustomObjectQueryPredicates builds the commercetools query predicates
+// for a container, optionally narrowed by a key prefix and/or an arbitrary
+// `where` predicate.
+func _buildCustomObjectQueryPredicates(container, keyPrefix, where string) []string {
+	predicates := []string{fmt.Sprintf(`container = "%s"`, container)}
+	if keyPrefix != "" {
+		predicates = append(predicates, fmt.Sprintf(`key like "%s*"`, keyPrefix))
+	}
+	if where != "" {
+		predicates = append(predicates, where)
+	}
+	return predicates
+}
+
+// _customObjectMatchesJSONPathFilter reports whether value matches a compiled
+// jsonpath_filter. filter is compiled once per read via jsonpath.New, so a
+// filter that doesn't parse at all (e.g. mismatched brackets) fails the read
+// immediately with that parse error instead of silently excluding every
+// object. Evaluating a syntactically valid filter against one particular
+// object can still fail, though, e.g. because that object's value doesn't
+// have the key the filter references; that's treated the same as the filter
+// evaluating to nil, i.e. "no match for this object", matching the
+// documented behavior that objects the filter doesn't match are excluded.
+func _customObjectMatchesJSONPathFilter(ctx context.Context, filter gval.Evaluable, value interface{}) bool {
+	match, err := filter(ctx, value)
+	return err == nil && match != nil
+}
+
+// _nextCustomObjectQueryOffset advances the query offset by pageCount and
+// reports whether the last page has been reached, i.e. the API returned
+// fewer objects than one full page can hold.
+func _nextCustomObjectQueryOffset(offset, pageCount, pageSize int) (nextOffset int, done bool) {
+	return offset + pageCount, pageCount < pageSize
+}
@@ -0,0 +1,110 @@
+package commercetools
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/PaesslerAG/jsonpath"
+)
+
+func TestBuildCustomObjectQueryPredicates(t *testing.T) {
+	tests := []struct {
+		name      string
+		container string
+		keyPrefix string
+		where     string
+		want      []string
+	}{
+		{
+			name:      "container only",
+			container: "flags",
+			want:      []string{`container = "flags"`},
+		},
+		{
+			name:      "with key prefix",
+			container: "flags",
+			keyPrefix: "beta-",
+			want:      []string{`container = "flags"`, `key like "beta-*"`},
+		},
+		{
+			name:      "with where",
+			container: "flags",
+			where:     `value(enabled) = true`,
+			want:      []string{`container = "flags"`, `value(enabled) = true`},
+		},
+		{
+			name:      "with key prefix and where",
+			container: "flags",
+			keyPrefix: "beta-",
+			where:     `value(enabled) = true`,
+			want:      []string{`container = "flags"`, `key like "beta-*"`, `value(enabled) = true`},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := _buildCustomObjectQueryPredicates(tt.container, tt.keyPrefix, tt.where)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCustomObjectMatchesJSONPathFilter(t *testing.T) {
+	value := map[string]interface{}{"enabled": true, "absent": nil}
+
+	tests := []struct {
+		name   string
+		filter string
+		want   bool
+	}{
+		{"matching key", "$.enabled", true},
+		{"key not present on this value", "$.missing", false},
+		{"explicit null value does not match", "$.absent", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filter, err := jsonpath.New(tt.filter)
+			if err != nil {
+				t.Fatalf("unexpected error compiling filter: %v", err)
+			}
+			if got := _customObjectMatchesJSONPathFilter(context.Background(), filter, value); got != tt.want {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCompilingInvalidJSONPathFilterFails(t *testing.T) {
+	if _, err := jsonpath.New("$[badsyntax"); err == nil {
+		t.Error("expected an error for a malformed jsonpath_filter")
+	}
+}
+
+func TestNextCustomObjectQueryOffset(t *testing.T) {
+	tests := []struct {
+		name           string
+		offset         int
+		pageCount      int
+		pageSize       int
+		wantNextOffset int
+		wantDone       bool
+	}{
+		{"full page continues", 0, 500, 500, 500, false},
+		{"partial page is the last one", 500, 37, 500, 537, true},
+		{"empty page is done", 0, 0, 500, 0, true},
+		{"exact multiple still continues until a short page", 1000, 500, 500, 1500, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotOffset, gotDone := _nextCustomObjectQueryOffset(tt.offset, tt.pageCount, tt.pageSize)
+			if gotOffset != tt.wantNextOffset || gotDone != tt.wantDone {
+				t.Errorf("got (%d, %v), want (%d, %v)", gotOffset, gotDone, tt.wantNextOffset, tt.wantDone)
+			}
+		})
+	}
+}
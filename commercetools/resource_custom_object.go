@@ -5,9 +5,20 @@ import (
 	"encoding/json"
 	"fmt"
 	"log"
+	"reflect"
+	"sort"
+	"strings"
 
+	jsonpatch "github.com/evanphx/json-patch"
 	"github.com/hashicorp/terraform-plugin-sdk/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/helper/validation"
 	"github.com/labd/commercetools-go-sdk/commercetools"
+	"k8s.io/apimachinery/pkg/util/jsonmergepatch"
+)
+
+const (
+	customObjectMergeStrategyReplace  = "replace"
+	customObjectMergeStrategyThreeWay = "three_way_merge"
 )
 
 func resourceCustomObject() *schema.Resource {
@@ -44,26 +55,126 @@ func resourceCustomObject() *schema.Resource {
 				Type:     schema.TypeInt,
 				Computed: true,
 			},
+			"merge_strategy": {
+				Description: "How to reconcile `value` with changes made to the live object outside of Terraform. " +
+					"One of `replace` (default, overwrites the whole value) or `three_way_merge` (computes a JSON " +
+					"merge patch from `last_applied_value`, the new `value` and the object's current live value, " +
+					"the same three-way merge `kubectl apply` uses, so keys written only by other systems, and absent " +
+					"from `value`, are preserved). " +
+					"`three_way_merge` requires `value` to be a JSON object, since the merge operates key by key; " +
+					"`replace` supports any of the JSON types `value` allows.",
+				Type:     schema.TypeString,
+				Optional: true,
+				Default:  customObjectMergeStrategyReplace,
+				ValidateFunc: validation.StringInSlice(
+					[]string{customObjectMergeStrategyReplace, customObjectMergeStrategyThreeWay}, false,
+				),
+			},
+			"last_applied_value": {
+				Description: "The JSON `value` Terraform last applied. Used internally to compute the three-way " +
+					"merge when `merge_strategy = \"three_way_merge\"`.",
+				Type:     schema.TypeString,
+				Computed: true,
+			},
+			"merge_conflicts": {
+				Description: "Top-level keys of `value` that `merge_strategy = \"three_way_merge\"` found changed " +
+					"both by Terraform (since `last_applied_value`) and out-of-band on the live object. `value` " +
+					"wins on these keys; only set after a three-way merge update.",
+				Type:     schema.TypeList,
+				Computed: true,
+				Elem:     &schema.Schema{Type: schema.TypeString},
+			},
+			"schema_json": {
+				Description: "A JSON Schema document. When set, `value` is validated against it at plan time and " +
+					"schema-declared defaults are applied to `value` before it is sent to commercetools. Mutually " +
+					"exclusive with `schema_ref`.",
+				Type:          schema.TypeString,
+				Optional:      true,
+				ConflictsWith: []string{"schema_ref"},
+			},
+			"schema_ref": {
+				Description: "The `key` of a `commercetools_custom_object_schema` resource to validate and " +
+					"default `value` against, instead of inlining the schema with `schema_json`. Mutually " +
+					"exclusive with `schema_json`.",
+				Type:          schema.TypeString,
+				Optional:      true,
+				ConflictsWith: []string{"schema_json"},
+			},
+			"allow_rename": {
+				Description: "Whether changing `container` or `key` is allowed. Custom objects are identified by " +
+					"container+key, so a rename moves the object to a new id: Terraform creates it at the new " +
+					"coordinates, deletes it at the old ones, and rolls the new object back if that delete fails. " +
+					"Set to false to reject such changes at plan time instead.",
+				Type:     schema.TypeBool,
+				Optional: true,
+				Default:  true,
+			},
 		},
+		CustomizeDiff: resourceCustomObjectCustomizeDiff,
 	}
 }
 
+// resourceCustomObjectCustomizeDiff validates `value` against `schema_json`
+// or `schema_ref` at plan time, so a request that would be rejected by
+// commercetools' optimistic write (or silently coerced) instead fails the
+// plan with the offending JSON pointer.
+func resourceCustomObjectCustomizeDiff(d *schema.ResourceDiff, m interface{}) error {
+	if d.Id() != "" && (d.HasChange("container") || d.HasChange("key")) {
+		if !d.Get("allow_rename").(bool) {
+			return fmt.Errorf("changing container/key is disallowed while allow_rename is false")
+		}
+		log.Printf("[WARN] container/key changed: this custom object's id will change since commercetools custom objects are identified by container+key")
+	}
+
+	raw := d.Get("value").(string)
+	if raw == "" {
+		return nil
+	}
+
+	if d.Get("merge_strategy").(string) == customObjectMergeStrategyThreeWay && !_isJSONObject([]byte(raw)) {
+		return fmt.Errorf(
+			`merge_strategy = "three_way_merge" only supports JSON object values for "value", since the merge operates key by key`,
+		)
+	}
+
+	rawSchema, err := _resolveCustomObjectSchema(context.Background(), getClient(m), d)
+	if err != nil {
+		return err
+	}
+	if rawSchema == "" {
+		return nil
+	}
+
+	compiled, err := _compileJSONSchema(rawSchema)
+	if err != nil {
+		return err
+	}
+	_, err = _validateAndDefaultCustomObjectValue(compiled, raw)
+	return err
+}
+
 func resourceCustomObjectCreate(d *schema.ResourceData, m interface{}) error {
 	client := getClient(m)
-	value := _decodeCustomObjectValue(d.Get("value").(string))
+	ctx := context.Background()
+
+	value, err := _resolveCustomObjectValue(ctx, client, d)
+	if err != nil {
+		return err
+	}
 
 	draft := commercetools.CustomObjectDraft{
 		Container: d.Get("container").(string),
 		Key:       d.Get("key").(string),
 		Value:     value,
 	}
-	customObject, err := client.CustomObjectCreate(context.Background(), &draft)
+	customObject, err := client.CustomObjectCreate(ctx, &draft)
 	if err != nil {
 		return err
 	}
 
 	d.SetId(customObject.ID)
 	d.Set("version", customObject.Version)
+	d.Set("last_applied_value", d.Get("value").(string))
 
 	return nil
 }
@@ -101,56 +212,172 @@ func resourceCustomObjectRead(d *schema.ResourceData, m interface{}) error {
 
 func resourceCustomObjectUpdate(d *schema.ResourceData, m interface{}) error {
 	client := getClient(m)
-	value := _decodeCustomObjectValue(d.Get("value").(string))
 	ctx := context.Background()
 
+	value, err := _resolveCustomObjectValue(ctx, client, d)
+	if err != nil {
+		return err
+	}
+
 	if d.HasChange("container") || d.HasChange("key") {
-		// If the container or key has changed we need to delete the old object
-		// and create the new object. We first want to create the new vlaue and
-		// then the old one
-		draft := commercetools.CustomObjectDraft{
-			Container: d.Get("container").(string),
-			Key:       d.Get("key").(string),
-			Value:     value,
-		}
-		customObject, err := client.CustomObjectCreate(ctx, &draft)
-		if err != nil {
-			return err
+		// Custom objects are identified by container+key, so changing either
+		// one moves the object to a new id. Reject that outright if the user
+		// has opted out of renames.
+		if !d.Get("allow_rename").(bool) {
+			return fmt.Errorf(
+				"container/key changed for custom object %s but allow_rename is false; this would move it to a new id",
+				d.Id(),
+			)
 		}
-		d.SetId(customObject.ID)
-		d.Set("version", customObject.Version)
 
-		_, err = client.CustomObjectDeleteWithContainerAndKey(
-			ctx,
-			d.Get("container").(string),
-			d.Get("key").(string),
-			d.Get("version").(int),
-			true,
-		)
+		oldContainerRaw, _ := d.GetChange("container")
+		oldKeyRaw, _ := d.GetChange("key")
+		oldContainer := oldContainerRaw.(string)
+		oldKey := oldKeyRaw.(string)
+		oldVersion := d.Get("version").(int)
+		newContainer := d.Get("container").(string)
+		newKey := d.Get("key").(string)
+
+		// Two-phase move: create the object at its new coordinates first,
+		// then delete it at the old ones. If the delete fails, roll back by
+		// deleting the object we just created so state keeps reflecting
+		// reality instead of silently leaving two live copies around.
+		err := retryOnVersionConflict(ctx, func() error {
+			draft := commercetools.CustomObjectDraft{
+				Container: newContainer,
+				Key:       newKey,
+				Value:     value,
+			}
+			customObject, err := client.CustomObjectCreate(ctx, &draft)
+			if err != nil {
+				return err
+			}
 
-		// Do we care? Just log an error for now
+			_, err = client.CustomObjectDeleteWithContainerAndKey(ctx, oldContainer, oldKey, oldVersion, false)
+			if err != nil {
+				// A 404 means the old object is already gone (e.g. a retried
+				// or previously-interrupted rename), so the move already
+				// happened from commercetools' point of view; nothing to
+				// roll back.
+				if isNotFoundError(err) {
+					d.SetId(customObject.ID)
+					d.Set("version", customObject.Version)
+					d.Set("last_applied_value", d.Get("value").(string))
+					return nil
+				}
+
+				if isVersionConflictError(err) {
+					if current, getErr := client.CustomObjectGetWithContainerAndKey(ctx, oldContainer, oldKey); getErr == nil {
+						oldVersion = current.Version
+					}
+				}
+				if _, rollbackErr := client.CustomObjectDeleteWithContainerAndKey(ctx, newContainer, newKey, customObject.Version, false); rollbackErr != nil {
+					log.Printf(
+						"[ERROR] failed to roll back custom object created at %s/%s after failing to delete the old object at %s/%s: %s",
+						newContainer, newKey, oldContainer, oldKey, rollbackErr,
+					)
+				}
+				return fmt.Errorf(
+					"could not delete custom object at previous coordinates %s/%s during rename, rolled back the new object: %w",
+					oldContainer, oldKey, err,
+				)
+			}
+
+			d.SetId(customObject.ID)
+			d.Set("version", customObject.Version)
+			d.Set("last_applied_value", d.Get("value").(string))
+			return nil
+		})
 		if err != nil {
-			log.Printf("Failed to remove old custom object")
+			return err
 		}
 	} else {
 
 		// Update the value by creating an object with the same key/value.
 		// Commercetools will then update the value of the object if it already
-		// exists
-		draft := commercetools.CustomObjectDraft{
-			Container: d.Get("container").(string),
-			Key:       d.Get("key").(string),
-			Value:     value,
-			Version:   d.Get("version").(int),
-		}
-		customObject, err := client.CustomObjectCreate(ctx, &draft)
+		// exists. Retry on version conflicts since the local version may be
+		// stale by the time the request reaches commercetools.
+		container := d.Get("container").(string)
+		key := d.Get("key").(string)
+		mergeStrategy := d.Get("merge_strategy").(string)
+
+		err := retryOnVersionConflict(ctx, func() error {
+			resolvedValue := value
+			version := d.Get("version").(int)
+
+			if mergeStrategy == customObjectMergeStrategyThreeWay {
+				current, getErr := client.CustomObjectGetWithContainerAndKey(ctx, container, key)
+				if getErr != nil {
+					return getErr
+				}
+				version = current.Version
+
+				// Use the resolved value (schema defaults already applied)
+				// as the new desired state, not the raw `value` attribute.
+				newDesired, marshalErr := json.Marshal(value)
+				if marshalErr != nil {
+					return fmt.Errorf("could not encode resolved value: %w", marshalErr)
+				}
+
+				oldDesired := []byte(d.Get("last_applied_value").(string))
+
+				merged, mergeErr := _mergeCustomObjectValue(oldDesired, newDesired, current.Value)
+				if mergeErr != nil {
+					return fmt.Errorf(
+						"could not three-way merge value for custom object with container %s and key %s: %w",
+						container, key, mergeErr,
+					)
+				}
+				resolvedValue = merged
+
+				currentJSON, marshalErr := json.Marshal(current.Value)
+				if marshalErr != nil {
+					return fmt.Errorf("could not encode current value: %w", marshalErr)
+				}
+				conflicts, conflictErr := _detectCustomObjectMergeConflicts(oldDesired, newDesired, currentJSON)
+				if conflictErr != nil {
+					return fmt.Errorf(
+						"could not detect merge conflicts for custom object with container %s and key %s: %w",
+						container, key, conflictErr,
+					)
+				}
+				if len(conflicts) > 0 {
+					log.Printf(
+						"[WARN] custom object %s/%s: value wins over out-of-band changes on conflicting keys: %s",
+						container, key, strings.Join(conflicts, ", "),
+					)
+				}
+				d.Set("merge_conflicts", conflicts)
+			} else {
+				d.Set("merge_conflicts", []string{})
+			}
+
+			draft := commercetools.CustomObjectDraft{
+				Container: container,
+				Key:       key,
+				Value:     resolvedValue,
+				Version:   version,
+			}
+			customObject, err := client.CustomObjectCreate(ctx, &draft)
+			if err != nil {
+				if isVersionConflictError(err) {
+					current, getErr := client.CustomObjectGetWithContainerAndKey(ctx, container, key)
+					if getErr != nil {
+						return getErr
+					}
+					d.Set("version", current.Version)
+				}
+				return err
+			}
+
+			d.SetId(customObject.ID)
+			d.Set("version", customObject.Version)
+			d.Set("last_applied_value", d.Get("value").(string))
+			return nil
+		})
 		if err != nil {
 			return err
 		}
-
-		d.SetId(customObject.ID)
-		d.Set("version", customObject.Version)
-
 	}
 	return nil
 }
@@ -158,6 +385,7 @@ func resourceCustomObjectUpdate(d *schema.ResourceData, m interface{}) error {
 func resourceCustomObjectDelete(d *schema.ResourceData, m interface{}) error {
 	container := d.Get("container").(string)
 	key := d.Get("key").(string)
+	ctx := context.Background()
 
 	client := getClient(m)
 
@@ -165,13 +393,19 @@ func resourceCustomObjectDelete(d *schema.ResourceData, m interface{}) error {
 	ctMutexKV.Lock(d.Id())
 	defer ctMutexKV.Unlock(d.Id())
 
-	customObject, err := client.CustomObjectGetWithContainerAndKey(context.Background(), container, key)
-	if err != nil {
-		return fmt.Errorf("could not get custom object with container %s and key %s: %w", container, key, err)
-	}
-	_, err = client.CustomObjectDeleteWithContainerAndKey(context.Background(), container, key, customObject.Version, false)
+	err := retryOnVersionConflict(ctx, func() error {
+		customObject, err := client.CustomObjectGetWithContainerAndKey(ctx, container, key)
+		if err != nil {
+			return fmt.Errorf("could not get custom object with container %s and key %s: %w", container, key, err)
+		}
+		_, err = client.CustomObjectDeleteWithContainerAndKey(ctx, container, key, customObject.Version, false)
+		if err != nil {
+			return fmt.Errorf("could not delete custom object with container %s and key %s: %w", container, key, err)
+		}
+		return nil
+	})
 	if err != nil {
-		return fmt.Errorf("could not delete custom object with container %s and key %s: %w", container, key, err)
+		return err
 	}
 	return nil
 }
@@ -181,3 +415,135 @@ func _decodeCustomObjectValue(value string) interface{} {
 	json.Unmarshal([]byte(value), &data)
 	return data
 }
+
+// _resolveCustomObjectValue decodes the raw `value` attribute and, if
+// `schema_json`/`schema_ref` resolve to a schema, validates it and applies
+// schema-declared defaults before it is sent to commercetools.
+func _resolveCustomObjectValue(ctx context.Context, client *commercetools.Client, d resourceGetter) (interface{}, error) {
+	raw := d.Get("value").(string)
+
+	rawSchema, err := _resolveCustomObjectSchema(ctx, client, d)
+	if err != nil {
+		return nil, err
+	}
+	if rawSchema == "" {
+		var decoded interface{}
+		if err := json.Unmarshal([]byte(raw), &decoded); err != nil {
+			return _decodeCustomObjectValue(raw), nil
+		}
+		return decoded, nil
+	}
+
+	compiled, err := _compileJSONSchema(rawSchema)
+	if err != nil {
+		return nil, err
+	}
+	return _validateAndDefaultCustomObjectValue(compiled, raw)
+}
+
+// _mergeCustomObjectValue computes a three-way JSON merge patch between
+// oldDesired (what Terraform last applied), newDesired (what Terraform wants
+// to apply now) and current (the live value read from commercetools), and
+// applies that patch to current. This mirrors the last-applied-configuration
+// approach kubectl/Helm use: keys Terraform removed from its own desired
+// state are removed, keys present only on current (in neither oldDesired nor
+// newDesired) are preserved, and every key in newDesired takes newDesired's
+// value, even where current changed it out-of-band.
+func _mergeCustomObjectValue(oldDesired, newDesired []byte, current interface{}) (interface{}, error) {
+	if len(oldDesired) == 0 {
+		oldDesired = []byte("{}")
+	}
+
+	if !_isJSONObject(newDesired) {
+		return nil, fmt.Errorf(
+			`merge_strategy = "three_way_merge" only supports JSON object values, since the merge operates key by key`,
+		)
+	}
+
+	currentJSON, err := json.Marshal(current)
+	if err != nil {
+		return nil, fmt.Errorf("could not marshal current value: %w", err)
+	}
+
+	patch, err := jsonmergepatch.CreateThreeWayJSONMergePatch(oldDesired, newDesired, currentJSON)
+	if err != nil {
+		return nil, fmt.Errorf("could not create three-way merge patch: %w", err)
+	}
+
+	merged, err := jsonpatch.MergePatch(currentJSON, patch)
+	if err != nil {
+		return nil, fmt.Errorf("could not apply merge patch: %w", err)
+	}
+
+	result := make(map[string]interface{})
+	if err := json.Unmarshal(merged, &result); err != nil {
+		return nil, fmt.Errorf("could not unmarshal merged value: %w", err)
+	}
+	return result, nil
+}
+
+// _detectCustomObjectMergeConflicts reports the top-level keys that were
+// changed both by Terraform (newDesired differs from oldDesired) and
+// out-of-band (current differs from oldDesired and from newDesired). On
+// those keys _mergeCustomObjectValue lets newDesired win silently, so
+// callers should surface the result to the user instead of swallowing it.
+func _detectCustomObjectMergeConflicts(oldDesired, newDesired, current []byte) ([]string, error) {
+	oldMap, err := _decodeJSONObject(oldDesired)
+	if err != nil {
+		return nil, fmt.Errorf("could not parse last_applied_value: %w", err)
+	}
+	newMap, err := _decodeJSONObject(newDesired)
+	if err != nil {
+		return nil, fmt.Errorf("could not parse value: %w", err)
+	}
+	currentMap, err := _decodeJSONObject(current)
+	if err != nil {
+		return nil, fmt.Errorf("could not parse current value: %w", err)
+	}
+
+	seen := make(map[string]bool)
+	var conflicts []string
+	for key := range oldMap {
+		seen[key] = true
+	}
+	for key := range newMap {
+		seen[key] = true
+	}
+	for key := range currentMap {
+		seen[key] = true
+	}
+
+	for key := range seen {
+		terraformChanged := !reflect.DeepEqual(oldMap[key], newMap[key])
+		externallyChanged := !reflect.DeepEqual(oldMap[key], currentMap[key])
+		sameResolution := reflect.DeepEqual(newMap[key], currentMap[key])
+		if terraformChanged && externallyChanged && !sameResolution {
+			conflicts = append(conflicts, key)
+		}
+	}
+
+	sort.Strings(conflicts)
+	return conflicts, nil
+}
+
+// _isJSONObject reports whether raw decodes to a JSON object, as opposed to
+// an array or a scalar (string/number/boolean/null).
+func _isJSONObject(raw []byte) bool {
+	var decoded interface{}
+	if err := json.Unmarshal(raw, &decoded); err != nil {
+		return false
+	}
+	_, ok := decoded.(map[string]interface{})
+	return ok
+}
+
+func _decodeJSONObject(raw []byte) (map[string]interface{}, error) {
+	if len(raw) == 0 {
+		return map[string]interface{}{}, nil
+	}
+	data := make(map[string]interface{})
+	if err := json.Unmarshal(raw, &data); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
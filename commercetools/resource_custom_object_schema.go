@@ -0,0 +1,165 @@
+package commercetools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+
+	"github.com/hashicorp/terraform-plugin-sdk/helper/schema"
+	"github.com/labd/commercetools-go-sdk/commercetools"
+)
+
+func resourceCustomObjectSchema() *schema.Resource {
+	return &schema.Resource{
+		Description: "Stores a JSON Schema document as a well-known custom object so multiple " +
+			"`commercetools_custom_object` resources can validate and default `value` against it by " +
+			"`schema_ref` instead of inlining the same `schema_json` everywhere. Backed by a custom object in " +
+			"the reserved `" + customObjectSchemaContainer + "` container.",
+		Create: resourceCustomObjectSchemaCreate,
+		Read:   resourceCustomObjectSchemaRead,
+		Update: resourceCustomObjectSchemaUpdate,
+		Delete: resourceCustomObjectSchemaDelete,
+		Importer: &schema.ResourceImporter{
+			State: schema.ImportStatePassthrough,
+		},
+		Schema: map[string]*schema.Schema{
+			"key": {
+				Description: "String matching the pattern '[-_~.a-zA-Z0-9]+', referenced by `schema_ref` on " +
+					"`commercetools_custom_object` resources",
+				Type:     schema.TypeString,
+				Required: true,
+				ForceNew: true,
+			},
+			"schema_json": {
+				Description: "The JSON Schema document",
+				Type:        schema.TypeString,
+				Required:    true,
+			},
+			"version": {
+				Type:     schema.TypeInt,
+				Computed: true,
+			},
+		},
+	}
+}
+
+func resourceCustomObjectSchemaCreate(d *schema.ResourceData, m interface{}) error {
+	client := getClient(m)
+	ctx := context.Background()
+
+	key := d.Get("key").(string)
+	if _, err := _compileJSONSchema(d.Get("schema_json").(string)); err != nil {
+		return err
+	}
+
+	draft := commercetools.CustomObjectDraft{
+		Container: customObjectSchemaContainer,
+		Key:       key,
+		Value:     _encodeCustomObjectSchema(d.Get("schema_json").(string)),
+	}
+	customObject, err := client.CustomObjectCreate(ctx, &draft)
+	if err != nil {
+		return err
+	}
+
+	d.SetId(customObject.ID)
+	d.Set("version", customObject.Version)
+	return nil
+}
+
+func resourceCustomObjectSchemaRead(d *schema.ResourceData, m interface{}) error {
+	key := d.Get("key").(string)
+	client := getClient(m)
+
+	customObject, err := client.CustomObjectGetWithContainerAndKey(context.Background(), customObjectSchemaContainer, key)
+	if err != nil {
+		if ctErr, ok := err.(commercetools.ErrorResponse); ok {
+			if ctErr.StatusCode == 404 {
+				d.SetId("")
+				return nil
+			}
+		}
+		return err
+	}
+
+	if customObject == nil {
+		log.Print("[DEBUG] No custom object schema found")
+		d.SetId("")
+		return nil
+	}
+
+	raw, err := _extractStoredCustomObjectSchema(customObject.Value)
+	if err != nil {
+		return err
+	}
+	d.Set("key", customObject.Key)
+	d.Set("schema_json", raw)
+	d.Set("version", customObject.Version)
+	return nil
+}
+
+func resourceCustomObjectSchemaUpdate(d *schema.ResourceData, m interface{}) error {
+	client := getClient(m)
+	ctx := context.Background()
+	key := d.Get("key").(string)
+
+	if _, err := _compileJSONSchema(d.Get("schema_json").(string)); err != nil {
+		return err
+	}
+
+	err := retryOnVersionConflict(ctx, func() error {
+		draft := commercetools.CustomObjectDraft{
+			Container: customObjectSchemaContainer,
+			Key:       key,
+			Value:     _encodeCustomObjectSchema(d.Get("schema_json").(string)),
+			Version:   d.Get("version").(int),
+		}
+		customObject, err := client.CustomObjectCreate(ctx, &draft)
+		if err != nil {
+			if isVersionConflictError(err) {
+				current, getErr := client.CustomObjectGetWithContainerAndKey(ctx, customObjectSchemaContainer, key)
+				if getErr != nil {
+					return getErr
+				}
+				d.Set("version", current.Version)
+			}
+			return err
+		}
+
+		d.SetId(customObject.ID)
+		d.Set("version", customObject.Version)
+		return nil
+	})
+	return err
+}
+
+func resourceCustomObjectSchemaDelete(d *schema.ResourceData, m interface{}) error {
+	key := d.Get("key").(string)
+	ctx := context.Background()
+	client := getClient(m)
+
+	ctMutexKV.Lock(d.Id())
+	defer ctMutexKV.Unlock(d.Id())
+
+	return retryOnVersionConflict(ctx, func() error {
+		customObject, err := client.CustomObjectGetWithContainerAndKey(ctx, customObjectSchemaContainer, key)
+		if err != nil {
+			return fmt.Errorf("could not get schema custom object with key %s: %w", key, err)
+		}
+		_, err = client.CustomObjectDeleteWithContainerAndKey(ctx, customObjectSchemaContainer, key, customObject.Version, false)
+		if err != nil {
+			return fmt.Errorf("could not delete schema custom object with key %s: %w", key, err)
+		}
+		return nil
+	})
+}
+
+// _encodeCustomObjectSchema wraps a raw JSON Schema document in the shape
+// stored on the backing custom object's value, as expected by
+// _extractStoredCustomObjectSchema.
+func _encodeCustomObjectSchema(rawSchema string) interface{} {
+	var decoded interface{}
+	json.Unmarshal([]byte(rawSchema), &decoded)
+	return map[string]interface{}{"schema": decoded}
+}
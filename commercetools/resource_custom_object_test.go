@@ -0,0 +1,193 @@
+package commercetools
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func TestMergeCustomObjectValue(t *testing.T) {
+	oldDesired := []byte(`{"a":"1","b":"1"}`)
+	newDesired := []byte(`{"a":"2","b":"1"}`)
+	current := map[string]interface{}{"a": "1", "b": "1", "c": "external"}
+
+	merged, err := _mergeCustomObjectValue(oldDesired, newDesired, current)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	result, ok := merged.(map[string]interface{})
+	if !ok {
+		t.Fatalf("expected map[string]interface{}, got %T", merged)
+	}
+
+	want := map[string]interface{}{"a": "2", "b": "1", "c": "external"}
+	if !reflect.DeepEqual(result, want) {
+		t.Errorf("got %v, want %v", result, want)
+	}
+}
+
+func TestMergeCustomObjectValueEmptyOldDesired(t *testing.T) {
+	merged, err := _mergeCustomObjectValue(nil, []byte(`{"a":"1"}`), map[string]interface{}{"b": "external"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	result, ok := merged.(map[string]interface{})
+	if !ok {
+		t.Fatalf("expected map[string]interface{}, got %T", merged)
+	}
+
+	want := map[string]interface{}{"a": "1", "b": "external"}
+	if !reflect.DeepEqual(result, want) {
+		t.Errorf("got %v, want %v", result, want)
+	}
+}
+
+func TestMergeCustomObjectValueRejectsNonObjectValues(t *testing.T) {
+	tests := []struct {
+		name       string
+		newDesired string
+		current    interface{}
+	}{
+		{"string", `"new"`, "current"},
+		{"number", `2`, 3.0},
+		{"array", `[2]`, []interface{}{3.0}},
+		{"bool", `true`, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := _mergeCustomObjectValue([]byte(`{}`), []byte(tt.newDesired), tt.current); err == nil {
+				t.Error("expected an error for a non-object value")
+			}
+		})
+	}
+}
+
+func TestIsJSONObject(t *testing.T) {
+	tests := []struct {
+		name string
+		raw  string
+		want bool
+	}{
+		{"object", `{"a":1}`, true},
+		{"empty object", `{}`, true},
+		{"string", `"a"`, false},
+		{"number", `1`, false},
+		{"array", `[1]`, false},
+		{"bool", `true`, false},
+		{"null", `null`, false},
+		{"invalid JSON", `not json`, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := _isJSONObject([]byte(tt.raw)); got != tt.want {
+				t.Errorf("_isJSONObject(%q) = %v, want %v", tt.raw, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDetectCustomObjectMergeConflicts(t *testing.T) {
+	tests := []struct {
+		name       string
+		oldDesired string
+		newDesired string
+		current    string
+		want       []string
+	}{
+		{
+			name:       "no changes",
+			oldDesired: `{"a":"1"}`,
+			newDesired: `{"a":"1"}`,
+			current:    `{"a":"1"}`,
+			want:       nil,
+		},
+		{
+			name:       "only terraform changed",
+			oldDesired: `{"a":"1"}`,
+			newDesired: `{"a":"2"}`,
+			current:    `{"a":"1"}`,
+			want:       nil,
+		},
+		{
+			name:       "only externally changed",
+			oldDesired: `{"a":"1"}`,
+			newDesired: `{"a":"1"}`,
+			current:    `{"a":"external"}`,
+			want:       nil,
+		},
+		{
+			name:       "both changed to the same value",
+			oldDesired: `{"a":"1"}`,
+			newDesired: `{"a":"2"}`,
+			current:    `{"a":"2"}`,
+			want:       nil,
+		},
+		{
+			name:       "both changed to different values is a conflict",
+			oldDesired: `{"a":"1","b":"1"}`,
+			newDesired: `{"a":"2","b":"1"}`,
+			current:    `{"a":"external","b":"1"}`,
+			want:       []string{"a"},
+		},
+		{
+			name:       "multiple conflicting keys are reported sorted",
+			oldDesired: `{"a":"1","b":"1"}`,
+			newDesired: `{"a":"2","b":"2"}`,
+			current:    `{"a":"external-a","b":"external-b"}`,
+			want:       []string{"a", "b"},
+		},
+		{
+			name:       "key added externally only is not a conflict",
+			oldDesired: `{}`,
+			newDesired: `{}`,
+			current:    `{"c":"external"}`,
+			want:       nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := _detectCustomObjectMergeConflicts([]byte(tt.oldDesired), []byte(tt.newDesired), []byte(tt.current))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			sort.Strings(got)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDecodeJSONObject(t *testing.T) {
+	t.Run("empty input", func(t *testing.T) {
+		got, err := _decodeJSONObject(nil)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if len(got) != 0 {
+			t.Errorf("expected empty map, got %v", got)
+		}
+	})
+
+	t.Run("valid object", func(t *testing.T) {
+		got, err := _decodeJSONObject([]byte(`{"a":1}`))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		want := map[string]interface{}{"a": float64(1)}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("got %v, want %v", got, want)
+		}
+	})
+
+	t.Run("invalid JSON", func(t *testing.T) {
+		if _, err := _decodeJSONObject([]byte(`not json`)); err == nil {
+			t.Error("expected an error for invalid JSON")
+		}
+	})
+}
@@ -0,0 +1,75 @@
+package commercetools
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"strings"
+	"time"
+
+	"github.com/labd/commercetools-go-sdk/commercetools"
+)
+
+// maxConflictRetries and conflictRetryBackoff bound how hard
+// retryOnVersionConflict retries. They are package-level vars rather than
+// consts so a future provider-level configuration option could override
+// them without threading an argument through every retryOnVersionConflict
+// call site; no such option exists yet.
+var (
+	maxConflictRetries   = 5
+	conflictRetryBackoff = 200 * time.Millisecond
+)
+
+// retryOnVersionConflict retries fn while it keeps failing with a
+// commercetools version conflict (HTTP 409 / ConcurrentModification),
+// backing off with jittered exponential delay between attempts. It is
+// modeled on client-go's util/retry.RetryOnConflict: fn is responsible for
+// re-fetching the current object, refreshing the local version and
+// rebuilding its request on every call, since the previous attempt's draft
+// is stale by definition once a conflict is observed.
+func retryOnVersionConflict(ctx context.Context, fn func() error) error {
+	backoff := conflictRetryBackoff
+
+	var err error
+	for attempt := 0; attempt <= maxConflictRetries; attempt++ {
+		err = fn()
+		if !isVersionConflictError(err) {
+			return err
+		}
+		if attempt == maxConflictRetries {
+			break
+		}
+
+		sleep := backoff + time.Duration(rand.Int63n(int64(backoff)))
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(sleep):
+		}
+		backoff *= 2
+	}
+	return err
+}
+
+// isVersionConflictError reports whether err represents a commercetools
+// optimistic concurrency failure.
+func isVersionConflictError(err error) bool {
+	if err == nil {
+		return false
+	}
+	var ctErr commercetools.ErrorResponse
+	if errors.As(err, &ctErr) && ctErr.StatusCode == 409 {
+		return true
+	}
+	return strings.Contains(err.Error(), "ConcurrentModification")
+}
+
+// isNotFoundError reports whether err represents a commercetools 404, i.e.
+// the object in question simply doesn't exist (any more).
+func isNotFoundError(err error) bool {
+	if err == nil {
+		return false
+	}
+	var ctErr commercetools.ErrorResponse
+	return errors.As(err, &ctErr) && ctErr.StatusCode == 404
+}
@@ -0,0 +1,112 @@
+package commercetools
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/labd/commercetools-go-sdk/commercetools"
+)
+
+func TestIsVersionConflictError(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"nil", nil, false},
+		{"409 response", commercetools.ErrorResponse{StatusCode: 409}, true},
+		{"404 response", commercetools.ErrorResponse{StatusCode: 404}, false},
+		{"concurrent modification message", errors.New("ConcurrentModification: version mismatch"), true},
+		{"unrelated error", errors.New("boom"), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isVersionConflictError(tt.err); got != tt.want {
+				t.Errorf("isVersionConflictError(%v) = %v, want %v", tt.err, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIsNotFoundError(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"nil", nil, false},
+		{"404 response", commercetools.ErrorResponse{StatusCode: 404}, true},
+		{"409 response", commercetools.ErrorResponse{StatusCode: 409}, false},
+		{"unrelated error", errors.New("boom"), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isNotFoundError(tt.err); got != tt.want {
+				t.Errorf("isNotFoundError(%v) = %v, want %v", tt.err, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRetryOnVersionConflictSucceedsAfterConflicts(t *testing.T) {
+	origBackoff := conflictRetryBackoff
+	conflictRetryBackoff = time.Millisecond
+	defer func() { conflictRetryBackoff = origBackoff }()
+
+	attempts := 0
+	err := retryOnVersionConflict(context.Background(), func() error {
+		attempts++
+		if attempts < 3 {
+			return commercetools.ErrorResponse{StatusCode: 409}
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("expected success, got %v", err)
+	}
+	if attempts != 3 {
+		t.Fatalf("expected 3 attempts, got %d", attempts)
+	}
+}
+
+func TestRetryOnVersionConflictGivesUpAfterMax(t *testing.T) {
+	origMax := maxConflictRetries
+	origBackoff := conflictRetryBackoff
+	maxConflictRetries = 2
+	conflictRetryBackoff = time.Millisecond
+	defer func() {
+		maxConflictRetries = origMax
+		conflictRetryBackoff = origBackoff
+	}()
+
+	attempts := 0
+	err := retryOnVersionConflict(context.Background(), func() error {
+		attempts++
+		return commercetools.ErrorResponse{StatusCode: 409}
+	})
+	if err == nil {
+		t.Fatal("expected an error once retries are exhausted")
+	}
+	if attempts != maxConflictRetries+1 {
+		t.Fatalf("expected %d attempts, got %d", maxConflictRetries+1, attempts)
+	}
+}
+
+func TestRetryOnVersionConflictPassesThroughNonConflictErrors(t *testing.T) {
+	attempts := 0
+	wantErr := errors.New("boom")
+	err := retryOnVersionConflict(context.Background(), func() error {
+		attempts++
+		return wantErr
+	})
+	if !errors.Is(err, wantErr) {
+		t.Fatalf("expected %v, got %v", wantErr, err)
+	}
+	if attempts != 1 {
+		t.Fatalf("expected a single attempt for a non-conflict error, got %d", attempts)
+	}
+}